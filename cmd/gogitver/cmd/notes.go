@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/syncromatics/gogitver/pkg/git"
+)
+
+var notesCmd = &cobra.Command{
+	Use:   "notes",
+	Short: "Composes release notes from the commits since the last tagged version",
+	Long: `notes walks the commits between the nearest semver tag reachable from HEAD and HEAD
+itself, and emits them as categorized release notes (Features / Fixes / Breaking Changes /
+Other) based on each commit's Conventional Commits type.`,
+	Run: runNotes,
+}
+
+func init() {
+	notesCmd.Flags().String("path", ".", "the path to the git repository")
+	notesCmd.Flags().String("worktree", "", "the path to a linked git worktree checkout, if different from --path")
+	notesCmd.Flags().String("git-dir", "", "the path to the repository's git directory, for bare repositories or a linked worktree's git-dir")
+	notesCmd.Flags().String("settings", "./.gogitver.yaml", "the file that contains the settings")
+	notesCmd.Flags().String("format", "markdown", "the output format: markdown, json, or keep-a-changelog")
+	notesCmd.Flags().BoolP("verbose", "v", false, "Show information about how the release notes were calculated")
+
+	rootCmd.AddCommand(notesCmd)
+}
+
+func runNotes(cmd *cobra.Command, args []string) {
+	r, s := getRepoAndSettings(cmd)
+	v := getBoolFromFlag(cmd, "verbose")
+
+	notes, err := git.GetReleaseNotes(r, s, v)
+	if err != nil {
+		panic(err)
+	}
+
+	format := git.ReleaseNotesFormat(cmd.Flag("format").Value.String())
+	rendered, err := notes.Render(format)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(rendered)
+}
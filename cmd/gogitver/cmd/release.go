@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/spf13/cobra"
+	"github.com/syncromatics/gogitver/pkg/git"
+)
+
+var releaseCmd = &cobra.Command{
+	Use:   "release [path...]",
+	Short: "Plans and optionally applies tags across one or more repositories",
+	Long: `release computes the next semver for each given repository path (or every
+immediate subdirectory of a workspace root) and prints a dry-run plan of
+(repo, currentVersion, nextVersion, reason) tuples. Pass --execute to create the
+tags for real, or --push to create them and push them to "origin"; without
+either, release only prints the plan.`,
+	Run: runRelease,
+}
+
+func init() {
+	releaseCmd.Flags().Bool("execute", false, "create the tags described by the plan instead of only printing it")
+	releaseCmd.Flags().Bool("push", false, "push created tags to \"origin\" (implies --execute)")
+	releaseCmd.Flags().String("sign-key", "", "path to an armored PGP private key to sign tags with; unsigned if omitted. Passphrase, if needed, comes from $GOGITVER_SIGN_KEY_PASSPHRASE")
+	releaseCmd.Flags().String("settings", "./.gogitver.yaml", "the file that contains the settings")
+	releaseCmd.Flags().String("convention", "", "override the commitConvention setting used to detect version bumps (regex|conventional)")
+	releaseCmd.Flags().String("ci", "", "force detection of a specific CI provider (travis|gitlab|github|circleci|jenkins|buildkite|drone) instead of auto-detecting")
+	releaseCmd.Flags().Bool("forbid-behind-master", false, "error if a repo's calculated version is behind the calculated version of refs/heads/master") // TODO: Deprecate 'master'
+	releaseCmd.Flags().Bool("trim-branch-prefix", false, "Trim branch prefixes feature/ and hotfix/ from prerelease label")
+	releaseCmd.Flags().BoolP("verbose", "v", false, "Show information about how the version was calculated")
+
+	rootCmd.AddCommand(releaseCmd)
+}
+
+func runRelease(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		fmt.Println("release requires at least one repository path")
+		os.Exit(1)
+	}
+
+	sf := cmd.Flag("settings")
+	var settings *git.Settings
+	_, err := os.Stat(sf.Value.String())
+	if sf.Changed || err == nil {
+		r, err := os.Open(sf.Value.String())
+		if err != nil {
+			panic(err)
+		}
+		settings, err = git.GetSettingsFromFile(r)
+		if err != nil {
+			panic(err)
+		}
+	} else {
+		settings = git.GetDefaultSettings()
+	}
+
+	if cf := cmd.Flag("convention"); cf != nil && cf.Value.String() != "" {
+		settings.CommitConvention = git.CommitConvention(cf.Value.String())
+	}
+
+	v := getBoolFromFlag(cmd, "verbose")
+	branchSettings := getBranchSettings(cmd)
+
+	plans, err := git.PlanRelease(args, settings, branchSettings, v)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, p := range plans {
+		fmt.Printf("%s\t%s -> %s\t%s\n", p.Path, p.CurrentVersion, p.NextVersion, p.Reason)
+	}
+
+	execute := getBoolFromFlag(cmd, "execute")
+	push := getBoolFromFlag(cmd, "push")
+	if !execute && !push {
+		return
+	}
+
+	var signKey *openpgp.Entity
+	if keyPath := cmd.Flag("sign-key").Value.String(); keyPath != "" {
+		signKey, err = git.LoadSignKey(keyPath, []byte(os.Getenv("GOGITVER_SIGN_KEY_PASSPHRASE")))
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	err = git.ExecuteRelease(plans, push, signKey)
+	if err != nil {
+		panic(err)
+	}
+}
@@ -10,8 +10,8 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/syncromatics/gogitver/pkg/git"
 
-	gogit "gopkg.in/src-d/go-git.v4"
-	"gopkg.in/src-d/go-git.v4/plumbing"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
 )
 
 var rootCmd = &cobra.Command{
@@ -32,12 +32,18 @@ func init() {
 	var cmds = [2]*cobra.Command{rootCmd, prereleaseCmd}
 	for _, cmd := range cmds {
 		cmd.Flags().String("path", ".", "the path to the git repository")
+		cmd.Flags().String("worktree", "", "the path to a linked git worktree checkout, if different from --path")
+		cmd.Flags().String("git-dir", "", "the path to the repository's git directory, for bare repositories or a linked worktree's git-dir")
 		cmd.Flags().String("settings", "./.gogitver.yaml", "the file that contains the settings")
+		cmd.Flags().String("convention", "", "override the commitConvention setting used to detect version bumps (regex|conventional)")
+		cmd.Flags().String("ci", "", "force detection of a specific CI provider (travis|gitlab|github|circleci|jenkins|buildkite|drone) instead of auto-detecting")
+		cmd.Flags().String("backend", git.BackendGoGit, "version computation backend: gogit (default, in-process) or exec (shells out to the system git binary)")
 		cmd.Flags().Bool("trim-branch-prefix", false, "Trim branch prefixes feature/ and hotfix/ from prerelease label")
 		cmd.Flags().BoolP("verbose", "v", false, "Show information about how the version was calculated")
 	}
 
 	rootCmd.Flags().Bool("forbid-behind-master", false, "error if the current branch's calculated version is behind the calculated version of refs/heads/master") // TODO: Deprecate 'master'
+	rootCmd.Flags().Bool("auto-unshallow", false, "if the repository doesn't have enough history to determine a version, automatically fetch the rest and retry")
 
 	rootCmd.AddCommand(prereleaseCmd)
 }
@@ -50,8 +56,24 @@ func Execute() {
 	}
 }
 
+// resolvedGitPaths applies the --worktree override to --path and reads --git-dir, the same way
+// for every caller that needs to address the repository on disk - getRepoAndSettings (via
+// go-git) and getBackend (via the exec backend) must agree on which repository they're each
+// opening.
+func resolvedGitPaths(cmd *cobra.Command) (path string, gitDir string) {
+	path = cmd.Flag("path").Value.String()
+	if wf := cmd.Flag("worktree"); wf != nil && wf.Value.String() != "" {
+		path = wf.Value.String()
+	}
+
+	if gf := cmd.Flag("git-dir"); gf != nil {
+		gitDir = gf.Value.String()
+	}
+
+	return path, gitDir
+}
+
 func getRepoAndSettings(cmd *cobra.Command) (*gogit.Repository, *git.Settings) {
-	f := cmd.Flag("path")
 	sf := cmd.Flag("settings")
 
 	var s *git.Settings
@@ -70,7 +92,17 @@ func getRepoAndSettings(cmd *cobra.Command) (*gogit.Repository, *git.Settings) {
 		s = git.GetDefaultSettings()
 	}
 
-	r, err := gogit.PlainOpen(f.Value.String())
+	if cf := cmd.Flag("convention"); cf != nil && cf.Value.String() != "" {
+		s.CommitConvention = git.CommitConvention(cf.Value.String())
+	}
+
+	path, gitDir := resolvedGitPaths(cmd)
+
+	r, err := git.OpenRepository(&git.OpenOptions{
+		Path:         path,
+		GitDir:       gitDir,
+		DetectDotGit: true,
+	})
 	if err != nil {
 		panic(err)
 	}
@@ -89,11 +121,31 @@ func getBoolFromFlag(cmd *cobra.Command, flagName string) bool {
 func getBranchSettings(cmd *cobra.Command) *git.BranchSettings {
 	fbm := getBoolFromFlag(cmd, "forbid-behind-master")
 	trimPrefix := getBoolFromFlag(cmd, "trim-branch-prefix")
+	ci := ""
+	if cf := cmd.Flag("ci"); cf != nil {
+		ci = cf.Value.String()
+	}
 	return &git.BranchSettings{
 		ForbidBehindDefaultBranch: fbm,
 		TrimBranchPrefix:          trimPrefix,
 		DefaultBranch:             plumbing.Master, // TODO: get from cmd
+		CIProvider:                ci,
+	}
+}
+
+func getBackend(cmd *cobra.Command, r *gogit.Repository) git.Backend {
+	name := ""
+	if bf := cmd.Flag("backend"); bf != nil {
+		name = bf.Value.String()
+	}
+
+	path, gitDir := resolvedGitPaths(cmd)
+	b, err := git.NewBackend(name, r, path, gitDir)
+	if err != nil {
+		panic(err)
 	}
+
+	return b
 }
 
 func runRoot(cmd *cobra.Command, args []string) {
@@ -105,7 +157,18 @@ func runRoot(cmd *cobra.Command, args []string) {
 	}
 
 	branchSettings := getBranchSettings(cmd)
-	version, err := git.GetCurrentVersion(r, s, branchSettings, v)
+	backend := getBackend(cmd, r)
+	version, err := backend.GetCurrentVersion(s, branchSettings, v)
+	if _, ok := err.(*git.ErrShallowHistory); ok && getBoolFromFlag(cmd, "auto-unshallow") {
+		path, _ := resolvedGitPaths(cmd)
+		if uerr := git.AutoUnshallow(path); uerr != nil {
+			panic(uerr)
+		}
+
+		r, s = getRepoAndSettings(cmd)
+		backend = getBackend(cmd, r)
+		version, err = backend.GetCurrentVersion(s, branchSettings, v)
+	}
 	if err != nil {
 		panic(err)
 	}
@@ -116,11 +179,17 @@ func runRoot(cmd *cobra.Command, args []string) {
 func runPrerelease(cmd *cobra.Command, args []string) {
 	r, s := getRepoAndSettings(cmd)
 	trimPrefix := getBoolFromFlag(cmd, "trim-branch-prefix")
+	ci := ""
+	if cf := cmd.Flag("ci"); cf != nil {
+		ci = cf.Value.String()
+	}
 	branchSettings := &git.BranchSettings{
 		TrimBranchPrefix: trimPrefix,
+		CIProvider:       ci,
 	}
 
-	label, err := git.GetPrereleaseLabel(r, s, branchSettings)
+	backend := getBackend(cmd, r)
+	label, err := backend.GetPrereleaseLabel(s, branchSettings)
 	if err != nil {
 		panic(err)
 	}
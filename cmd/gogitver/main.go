@@ -0,0 +1,7 @@
+package main
+
+import "github.com/syncromatics/gogitver/cmd/gogitver/cmd"
+
+func main() {
+	cmd.Execute()
+}
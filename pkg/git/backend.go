@@ -0,0 +1,445 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/coreos/go-semver/semver"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/pkg/errors"
+)
+
+// Backend computes versions and prerelease labels for a single repository. gogitver ships two
+// implementations: the default go-git based one, and an exec one that shells out to the
+// installed git binary.
+type Backend interface {
+	GetCurrentVersion(settings *Settings, branchSettings *BranchSettings, verbose bool) (string, error)
+	GetPrereleaseLabel(settings *Settings, branchSettings *BranchSettings) (string, error)
+}
+
+// Backend names accepted by the --backend flag.
+const (
+	BackendGoGit = "gogit"
+	BackendExec  = "exec"
+)
+
+// NewBackend constructs the named Backend. repo is used by the gogit backend; path and gitDir
+// are used by the exec backend to invoke the system git binary against the right repository -
+// gitDir mirrors OpenOptions.GitDir, taking precedence over path the same way it does for
+// OpenRepository, so a linked worktree's private git-dir or a bare repository work the same way
+// for both backends. An empty name selects BackendGoGit.
+func NewBackend(name string, repo *gogit.Repository, path string, gitDir string) (Backend, error) {
+	switch name {
+	case "", BackendGoGit:
+		return &gogitBackend{repo: repo}, nil
+	case BackendExec:
+		return &execBackend{path: path, gitDir: gitDir}, nil
+	default:
+		return nil, errors.Errorf("unknown backend %q", name)
+	}
+}
+
+// gogitBackend is the original, default Backend: it walks history in-process using go-git.
+type gogitBackend struct {
+	repo *gogit.Repository
+}
+
+func (b *gogitBackend) GetCurrentVersion(settings *Settings, branchSettings *BranchSettings, verbose bool) (string, error) {
+	return GetCurrentVersion(b.repo, settings, branchSettings, verbose)
+}
+
+func (b *gogitBackend) GetPrereleaseLabel(settings *Settings, branchSettings *BranchSettings) (string, error) {
+	return GetPrereleaseLabel(b.repo, settings, branchSettings)
+}
+
+// execBackend shells out to the installed git binary instead of walking history with go-git.
+// `git describe` is dramatically faster than an in-process ref walk on large histories.
+type execBackend struct {
+	path string
+	// gitDir, if set, is passed to every git invocation as --git-dir instead of relying on
+	// path as the working directory - this is how a linked worktree's private git-dir or a
+	// bare repository are addressed, since git resolves both correctly via --git-dir
+	// regardless of the process's cwd, the same way OpenOptions.GitDir does for the gogit
+	// backend.
+	gitDir string
+}
+
+// getShallowHashes returns the set of commit hashes recorded in this repository's shallow file
+// - the exec-backend equivalent of shallow.go's getShallowHashes, which reads the same boundary
+// set via go-git's Storer.Shallow(). Reading it from the common dir (rather than a linked
+// worktree's private git-dir) matches where go-git's Storer itself keeps it.
+func (b *execBackend) getShallowHashes() (map[string]bool, error) {
+	gitDir, err := b.run("rev-parse", "--git-common-dir")
+	if err != nil {
+		return nil, err
+	}
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(b.path, gitDir)
+	}
+
+	data, err := os.ReadFile(filepath.Join(gitDir, "shallow"))
+	if os.IsNotExist(err) {
+		return map[string]bool{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read shallow file")
+	}
+
+	result := make(map[string]bool)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			result[line] = true
+		}
+	}
+	return result, nil
+}
+
+func (b *execBackend) run(args ...string) (string, error) {
+	if b.gitDir != "" {
+		args = append([]string{"--git-dir=" + b.gitDir}, args...)
+	}
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = b.path
+	out, err := cmd.Output()
+	if err != nil {
+		return "", errors.Wrapf(err, "git %s failed", strings.Join(args, " "))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (b *execBackend) GetCurrentVersion(settings *Settings, branchSettings *BranchSettings, verbose bool) (version string, err error) {
+	if !branchSettings.IgnoreEnvVars {
+		ci, err := resolveCIProvider(branchSettings.CIProvider)
+		if err != nil {
+			return "", errors.Wrap(err, "GetCurrentVersion failed")
+		}
+		if ci != nil {
+			if tag, ok := ci.Tag(); ok {
+				v, err := parseTag(tag)
+				if err != nil {
+					return "", err
+				}
+				return v.String(), nil
+			}
+		}
+	}
+
+	defaultRef, err := b.resolveDefaultBranchRef(branchSettings.DefaultBranch)
+	if err != nil {
+		return "", errors.Wrap(err, "GetCurrentVersion failed")
+	}
+
+	shallowHashes, err := b.getShallowHashes()
+	if err != nil {
+		return "", errors.Wrap(err, "GetCurrentVersion failed")
+	}
+
+	masterVersion, err := b.versionAt(settings, defaultRef, shallowHashes)
+	if err != nil {
+		if _, ok := err.(*ErrShallowHistory); ok {
+			return "", err
+		}
+		return "", errors.Wrap(err, "GetCurrentVersion failed")
+	}
+
+	headHash, err := b.run("rev-parse", "HEAD")
+	if err != nil {
+		return "", errors.Wrap(err, "GetCurrentVersion failed")
+	}
+
+	defaultHash, err := b.run("rev-parse", defaultRef)
+	if err != nil {
+		return "", errors.Wrap(err, "GetCurrentVersion failed")
+	}
+
+	if headHash == defaultHash {
+		return masterVersion.String(), nil
+	}
+
+	// HEAD has diverged from the default branch: walk HEAD's first-parent ancestry down to
+	// either defaultHash or a tag, then apply the exact same base-version/bump rules the gogit
+	// backend's getVersion uses, so the two backends produce identical prerelease versions.
+	branchName, err := b.currentBranchName(branchSettings)
+	if err != nil {
+		return "", errors.Wrap(err, "GetCurrentVersion failed")
+	}
+
+	tagMap, err := b.buildTagMap()
+	if err != nil {
+		return "", errors.Wrap(err, "GetCurrentVersion failed")
+	}
+
+	parser, err := newCommitParser(settings)
+	if err != nil {
+		return "", err
+	}
+
+	versionMap, err := b.buildVersionMap(parser, tagMap, defaultHash, shallowHashes)
+	if err != nil {
+		if _, ok := err.(*ErrShallowHistory); ok {
+			return "", err
+		}
+		return "", errors.Wrap(err, "GetCurrentVersion failed")
+	}
+
+	branchVersion, err := computeVersionFromMap(versionMap, masterVersion)
+	if err != nil {
+		return "", errors.Wrap(err, "GetCurrentVersion failed")
+	}
+
+	shortHash := headHash[:4]
+	branchVersion.PreRelease = semver.PreRelease(fmt.Sprintf("%s-%d-%s", branchName, len(versionMap)-1, shortHash))
+
+	if branchSettings.ForbidBehindDefaultBranch && branchVersion.LessThan(*masterVersion) {
+		return "", errors.Errorf("Branch has calculated version '%s' whose version is less than master '%s'", branchVersion.String(), masterVersion)
+	}
+
+	return branchVersion.String(), nil
+}
+
+// buildTagMap indexes every tag in the repository by the hash of the commit it points at - the
+// exec-backend equivalent of git.go's buildTagMap, built from `git for-each-ref` instead of
+// walking tag references with go-git.
+func (b *execBackend) buildTagMap() (map[string]string, error) {
+	out, err := b.run("for-each-ref", "--format=%(objectname) %(*objectname) %(refname:short)", "refs/tags")
+	if err != nil {
+		return nil, err
+	}
+
+	tagMap := make(map[string]string)
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		switch len(fields) {
+		case 2: // lightweight tag: objectname is the commit itself
+			tagMap[fields[0]] = fields[1]
+		case 3: // annotated tag: *objectname is the commit it dereferences to
+			tagMap[fields[1]] = fields[2]
+		}
+	}
+
+	return tagMap, nil
+}
+
+// buildVersionMap walks HEAD's first-parent ancestry, stopping at stopHash or the first tagged
+// commit reached, whichever comes first - the exec-backend equivalent of branchWalker.GetVersionMap.
+// It fetches every commit's hash and message in a single `git log` call rather than one
+// subprocess per commit - the same reason versionAt below uses a single `git log` call instead
+// of per-commit `git show`. If it instead runs out of history at a recorded shallow boundary
+// without reaching stopHash or a tag, it returns *ErrShallowHistory, the same way
+// branchWalker.GetVersionMap does when commit.Parent(0) fails on a shallow boundary commit.
+func (b *execBackend) buildVersionMap(parser commitParser, tagMap map[string]string, stopHash string, shallowHashes map[string]bool) ([]gitVersion, error) {
+	out, err := b.run("log", "--first-parent", "--pretty=format:%H%x01%B%x02", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	result := []gitVersion{}
+	lastHash := ""
+	for _, record := range strings.Split(out, "\x02") {
+		record = strings.TrimSpace(record)
+		if record == "" {
+			continue
+		}
+
+		parts := strings.SplitN(record, "\x01", 2)
+		hash := strings.TrimSpace(parts[0])
+		message := ""
+		if len(parts) == 2 {
+			message = parts[1]
+		}
+		lastHash = hash
+
+		if hash == stopHash {
+			return result, nil
+		}
+
+		isSolid := false
+		var name *semver.Version
+		if tag, ok := tagMap[hash]; ok {
+			v, err := parseTag(tag)
+			if err == nil {
+				isSolid = true
+				name = v
+			}
+		}
+
+		major, minor, patch := parser.Parse(message)
+		result = append(result, gitVersion{
+			IsSolid:   isSolid,
+			Name:      name,
+			MajorBump: major,
+			MinorBump: minor,
+			PatchBump: patch,
+			Commit:    hash,
+		})
+
+		if isSolid {
+			return result, nil
+		}
+	}
+
+	if shallowHashes[lastHash] {
+		return result, &ErrShallowHistory{MinDepth: len(result) + 1}
+	}
+
+	return result, nil
+}
+
+// versionAt computes the semver at ref the same way the plain (no-divergence) case always did:
+// the nearest tag reachable from ref via `git describe`, bumped by every commit between that
+// tag and ref according to settings' commit convention. If no tag is reachable and the
+// repository has shallow boundaries, it returns *ErrShallowHistory instead of silently basing
+// the version on 0.0.0 - `git describe` can't tell us whether a tag exists beyond a boundary it
+// didn't fetch.
+func (b *execBackend) versionAt(settings *Settings, ref string, shallowHashes map[string]bool) (*semver.Version, error) {
+	describe, err := b.run("describe", "--tags", "--long", "--always", ref)
+	if err != nil {
+		return nil, err
+	}
+
+	tag, distance, err := parseDescribe(describe)
+	if err != nil {
+		return nil, err
+	}
+
+	if tag == "" && len(shallowHashes) > 0 {
+		minDepth := 1
+		if count, cerr := b.run("rev-list", "--count", ref); cerr == nil {
+			if n, aerr := strconv.Atoi(count); aerr == nil {
+				minDepth = n + 1
+			}
+		}
+		return nil, &ErrShallowHistory{MinDepth: minDepth}
+	}
+
+	base := &semver.Version{}
+	if tag != "" {
+		base, err = parseTag(tag)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// tag == "" always parses to distance 0 (parseDescribe's "no tags reachable" sentinel, not
+	// a real zero-distance match), so only short-circuit here when ref is genuinely sitting on
+	// a found tag - otherwise every commit back to the root still needs its bump applied below.
+	if tag != "" && distance == 0 {
+		return base, nil
+	}
+
+	parser, err := newCommitParser(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	rangeSpec := ref
+	if tag != "" {
+		rangeSpec = tag + ".." + ref
+	}
+
+	log, err := b.run("log", "--first-parent", "--reverse", "--pretty=format:%B%x00", rangeSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, message := range strings.Split(log, "\x00") {
+		message = strings.TrimSpace(message)
+		if message == "" {
+			continue
+		}
+
+		major, minor, patch := parser.Parse(message)
+		switch {
+		case major:
+			base.BumpMajor()
+		case minor:
+			base.BumpMinor()
+		case patch:
+			base.BumpPatch()
+		}
+	}
+
+	return base, nil
+}
+
+// resolveDefaultBranchRef mirrors getDefaultBranch's fallback chain (configured branch, then
+// "master", then the remote's HEAD) using the system git binary instead of go-git.
+func (b *execBackend) resolveDefaultBranchRef(defaultBranch plumbing.ReferenceName) (string, error) {
+	candidates := []string{}
+	if defaultBranch != "" {
+		candidates = append(candidates, defaultBranch.Short())
+	}
+	if defaultBranch != plumbing.Master {
+		candidates = append(candidates, plumbing.Master.Short())
+	}
+
+	for _, c := range candidates {
+		if _, err := b.run("rev-parse", "--verify", c); err == nil {
+			return c, nil
+		}
+	}
+
+	if ref, err := b.run("symbolic-ref", "refs/remotes/origin/HEAD"); err == nil {
+		// Keep the full "refs/remotes/origin/<branch>" form rather than trimming to
+		// "<branch>": a shallow/CI checkout commonly has the remote-tracking ref but no
+		// local branch of the same name, so the bare name wouldn't resolve.
+		return ref, nil
+	}
+
+	return "", errors.New("failed to get default branch")
+}
+
+func (b *execBackend) currentBranchName(branchSettings *BranchSettings) (string, error) {
+	if !branchSettings.IgnoreEnvVars {
+		ci, err := resolveCIProvider(branchSettings.CIProvider)
+		if err != nil {
+			return "", err
+		}
+		if ci != nil {
+			if name, ok := ci.Branch(); ok {
+				return cleanseBranchName(name, branchSettings.TrimBranchPrefix)
+			}
+		}
+	}
+
+	name, err := b.run("rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "", err
+	}
+
+	return cleanseBranchName(name, branchSettings.TrimBranchPrefix)
+}
+
+func (b *execBackend) GetPrereleaseLabel(settings *Settings, branchSettings *BranchSettings) (string, error) {
+	return b.currentBranchName(branchSettings)
+}
+
+// parseDescribe splits the output of `git describe --tags --long --always` into the tag it
+// found and how many commits HEAD is ahead of it. When no tag is reachable, `git describe`
+// falls back to a bare abbreviated hash; parseDescribe reports that as tag "", distance 0.
+func parseDescribe(describe string) (tag string, distance int, err error) {
+	idx := strings.LastIndex(describe, "-g")
+	if idx == -1 {
+		return "", 0, nil
+	}
+
+	rest := describe[:idx]
+	distIdx := strings.LastIndex(rest, "-")
+	if distIdx == -1 {
+		return "", 0, errors.Errorf("unexpected git describe output %q", describe)
+	}
+
+	distance, err = strconv.Atoi(rest[distIdx+1:])
+	if err != nil {
+		return "", 0, errors.Wrapf(err, "unexpected git describe output %q", describe)
+	}
+
+	return rest[:distIdx], distance, nil
+}
@@ -0,0 +1,64 @@
+package git
+
+import "testing"
+
+func TestParseDescribe(t *testing.T) {
+	cases := []struct {
+		name     string
+		describe string
+		tag      string
+		distance int
+		wantErr  bool
+	}{
+		{
+			name:     "on a tag",
+			describe: "v1.0.0-0-gabcdef1",
+			tag:      "v1.0.0",
+			distance: 0,
+		},
+		{
+			name:     "ahead of a tag",
+			describe: "v1.2.3-4-gabcdef1",
+			tag:      "v1.2.3",
+			distance: 4,
+		},
+		{
+			name:     "no tags reachable",
+			describe: "abcdef1",
+			tag:      "",
+			distance: 0,
+		},
+		{
+			name:     "tag name itself containing a hyphen",
+			describe: "release-1.0.0-2-gabcdef1",
+			tag:      "release-1.0.0",
+			distance: 2,
+		},
+		{
+			name:     "malformed distance",
+			describe: "v1.0.0-x-gabcdef1",
+			wantErr:  true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tag, distance, err := parseDescribe(c.describe)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("parseDescribe(%q) expected an error, got none", c.describe)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseDescribe(%q) unexpected error: %v", c.describe, err)
+			}
+			if tag != c.tag {
+				t.Errorf("tag = %q, want %q", tag, c.tag)
+			}
+			if distance != c.distance {
+				t.Errorf("distance = %d, want %d", distance, c.distance)
+			}
+		})
+	}
+}
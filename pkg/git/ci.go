@@ -0,0 +1,164 @@
+package git
+
+import (
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// CIProvider resolves the tag and branch gogitver should use for the current build from a
+// CI system's environment variables, so gogitver doesn't have to rely on go-git's (sometimes
+// detached, sometimes shallow) view of HEAD.
+type CIProvider interface {
+	// Name identifies the provider for the --ci flag, e.g. "travis".
+	Name() string
+	// Detect reports whether the current environment looks like it's running under this
+	// provider.
+	Detect() bool
+	// Tag returns the tag being built, if this build was triggered by a tag push.
+	Tag() (string, bool)
+	// Branch returns the branch being built, preferring a pull/merge request's source
+	// branch over the target branch when the provider distinguishes the two.
+	Branch() (string, bool)
+}
+
+// ciProviders is the registry of CIProvider implementations consulted by DetectCIProvider and
+// GetCIProvider, in priority order.
+var ciProviders = []CIProvider{
+	&travisCIProvider{},
+	&gitlabCIProvider{},
+	&githubActionsCIProvider{},
+	&circleCIProvider{},
+	&jenkinsCIProvider{},
+	&buildkiteCIProvider{},
+	&droneCIProvider{},
+}
+
+// DetectCIProvider returns the first registered CIProvider whose environment variables are
+// present, or nil if none match.
+func DetectCIProvider() CIProvider {
+	for _, p := range ciProviders {
+		if p.Detect() {
+			return p
+		}
+	}
+	return nil
+}
+
+// GetCIProvider looks up a registered CIProvider by the name accepted by the --ci flag.
+func GetCIProvider(name string) (CIProvider, error) {
+	for _, p := range ciProviders {
+		if p.Name() == name {
+			return p, nil
+		}
+	}
+	return nil, errors.Errorf("unknown CI provider %q", name)
+}
+
+// resolveCIProvider returns the CIProvider forced by name, or the auto-detected one when name
+// is empty. It returns a nil CIProvider (not an error) when auto-detection finds nothing.
+func resolveCIProvider(name string) (CIProvider, error) {
+	if name != "" {
+		return GetCIProvider(name)
+	}
+	return DetectCIProvider(), nil
+}
+
+type travisCIProvider struct{}
+
+func (travisCIProvider) Name() string { return "travis" }
+func (travisCIProvider) Detect() bool { return os.Getenv("TRAVIS") == "true" }
+func (travisCIProvider) Tag() (string, bool) {
+	return lookupNonEmptyEnv("TRAVIS_TAG")
+}
+func (travisCIProvider) Branch() (string, bool) {
+	if branch, ok := lookupNonEmptyEnv("TRAVIS_PULL_REQUEST_BRANCH"); ok {
+		return branch, true
+	}
+	return lookupNonEmptyEnv("TRAVIS_BRANCH")
+}
+
+type gitlabCIProvider struct{}
+
+func (gitlabCIProvider) Name() string { return "gitlab" }
+func (gitlabCIProvider) Detect() bool { return os.Getenv("GITLAB_CI") == "true" }
+func (gitlabCIProvider) Tag() (string, bool) {
+	return lookupNonEmptyEnv("CI_COMMIT_TAG")
+}
+func (gitlabCIProvider) Branch() (string, bool) {
+	return lookupNonEmptyEnv("CI_COMMIT_REF_NAME")
+}
+
+type githubActionsCIProvider struct{}
+
+func (githubActionsCIProvider) Name() string { return "github" }
+func (githubActionsCIProvider) Detect() bool { return os.Getenv("GITHUB_ACTIONS") == "true" }
+func (githubActionsCIProvider) Tag() (string, bool) {
+	ref, ok := lookupNonEmptyEnv("GITHUB_REF")
+	if !ok || !strings.HasPrefix(ref, "refs/tags/") {
+		return "", false
+	}
+	return lookupNonEmptyEnv("GITHUB_REF_NAME")
+}
+func (githubActionsCIProvider) Branch() (string, bool) {
+	if branch, ok := lookupNonEmptyEnv("GITHUB_HEAD_REF"); ok {
+		return branch, true
+	}
+	return lookupNonEmptyEnv("GITHUB_REF_NAME")
+}
+
+type circleCIProvider struct{}
+
+func (circleCIProvider) Name() string { return "circleci" }
+func (circleCIProvider) Detect() bool { return os.Getenv("CIRCLECI") == "true" }
+func (circleCIProvider) Tag() (string, bool) {
+	return lookupNonEmptyEnv("CIRCLE_TAG")
+}
+func (circleCIProvider) Branch() (string, bool) {
+	return lookupNonEmptyEnv("CIRCLE_BRANCH")
+}
+
+type jenkinsCIProvider struct{}
+
+func (jenkinsCIProvider) Name() string { return "jenkins" }
+func (jenkinsCIProvider) Detect() bool { return os.Getenv("JENKINS_URL") != "" }
+func (jenkinsCIProvider) Tag() (string, bool) {
+	return lookupNonEmptyEnv("TAG_NAME")
+}
+func (jenkinsCIProvider) Branch() (string, bool) {
+	if branch, ok := lookupNonEmptyEnv("CHANGE_BRANCH"); ok {
+		return branch, true
+	}
+	return lookupNonEmptyEnv("BRANCH_NAME")
+}
+
+type buildkiteCIProvider struct{}
+
+func (buildkiteCIProvider) Name() string { return "buildkite" }
+func (buildkiteCIProvider) Detect() bool { return os.Getenv("BUILDKITE") == "true" }
+func (buildkiteCIProvider) Tag() (string, bool) {
+	return lookupNonEmptyEnv("BUILDKITE_TAG")
+}
+func (buildkiteCIProvider) Branch() (string, bool) {
+	return lookupNonEmptyEnv("BUILDKITE_BRANCH")
+}
+
+type droneCIProvider struct{}
+
+func (droneCIProvider) Name() string { return "drone" }
+func (droneCIProvider) Detect() bool { return os.Getenv("DRONE") == "true" }
+func (droneCIProvider) Tag() (string, bool) {
+	return lookupNonEmptyEnv("DRONE_TAG")
+}
+func (droneCIProvider) Branch() (string, bool) {
+	return lookupNonEmptyEnv("DRONE_BRANCH")
+}
+
+func lookupNonEmptyEnv(name string) (string, bool) {
+	v, ok := os.LookupEnv(name)
+	if !ok || v == "" {
+		return "", false
+	}
+	return v, true
+}
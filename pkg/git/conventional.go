@@ -0,0 +1,91 @@
+package git
+
+import (
+	"regexp"
+	"strings"
+)
+
+var conventionalCommitPattern = regexp.MustCompile(`^(?P<type>[a-zA-Z]+)(\((?P<scope>[^)]+)\))?(?P<breaking>!)?:\s?(?P<description>.+)`)
+
+// ConventionalCommit is the parsed form of a Conventional Commits
+// (https://www.conventionalcommits.org/) commit message.
+type ConventionalCommit struct {
+	Type        string
+	Scope       string
+	Breaking    bool
+	Description string
+}
+
+// ParseConventionalCommit parses a commit message's subject line as a Conventional Commit,
+// e.g. "feat(api): add widgets" or "fix!: stop dropping events". ok is false if the subject
+// line doesn't match the convention.
+func ParseConventionalCommit(message string) (commit *ConventionalCommit, ok bool) {
+	subject := strings.SplitN(message, "\n", 2)[0]
+
+	match := conventionalCommitPattern.FindStringSubmatch(subject)
+	if match == nil {
+		return nil, false
+	}
+
+	commit = &ConventionalCommit{}
+	for i, name := range conventionalCommitPattern.SubexpNames() {
+		switch name {
+		case "type":
+			commit.Type = strings.ToLower(match[i])
+		case "scope":
+			commit.Scope = match[i]
+		case "breaking":
+			commit.Breaking = match[i] == "!"
+		case "description":
+			commit.Description = match[i]
+		}
+	}
+
+	if strings.Contains(message, "BREAKING CHANGE:") || strings.Contains(message, "BREAKING-CHANGE:") {
+		commit.Breaking = true
+	}
+
+	return commit, true
+}
+
+// conventionalCommitParser implements commitParser by classifying a commit's type against
+// Settings.ConventionalCommitTypes, falling back to the Conventional Commits defaults of
+// feat -> minor and fix -> patch. A "!" after the type/scope, or a BREAKING CHANGE trailer,
+// always forces a major bump regardless of the type mapping.
+type conventionalCommitParser struct {
+	typeBumps map[string]string
+}
+
+func newConventionalCommitParser(settings *Settings) *conventionalCommitParser {
+	bumps := map[string]string{
+		"feat": "minor",
+		"fix":  "patch",
+	}
+	for t, b := range settings.ConventionalCommitTypes {
+		bumps[t] = b
+	}
+
+	return &conventionalCommitParser{typeBumps: bumps}
+}
+
+func (p *conventionalCommitParser) Parse(message string) (major, minor, patch bool) {
+	commit, ok := ParseConventionalCommit(message)
+	if !ok {
+		return false, false, false
+	}
+
+	if commit.Breaking {
+		return true, false, false
+	}
+
+	switch p.typeBumps[commit.Type] {
+	case "major":
+		return true, false, false
+	case "minor":
+		return false, true, false
+	case "patch":
+		return false, false, true
+	default:
+		return false, false, false
+	}
+}
@@ -0,0 +1,73 @@
+package git
+
+import "testing"
+
+func TestParseConventionalCommit(t *testing.T) {
+	cases := []struct {
+		name       string
+		message    string
+		ok         bool
+		commitType string
+		scope      string
+		breaking   bool
+	}{
+		{
+			name:       "simple feat",
+			message:    "feat: add widgets",
+			ok:         true,
+			commitType: "feat",
+		},
+		{
+			name:       "scoped fix",
+			message:    "fix(api): stop dropping events",
+			ok:         true,
+			commitType: "fix",
+			scope:      "api",
+		},
+		{
+			name:       "breaking suffix",
+			message:    "feat!: drop legacy endpoint",
+			ok:         true,
+			commitType: "feat",
+			breaking:   true,
+		},
+		{
+			name:       "breaking change trailer",
+			message:    "feat: add widgets\n\nBREAKING CHANGE: removes the old widget format",
+			ok:         true,
+			commitType: "feat",
+			breaking:   true,
+		},
+		{
+			name:    "colon mid-subject is not a prefix",
+			message: "Document how to fix: typo in README",
+			ok:      false,
+		},
+		{
+			name:    "no colon at all",
+			message: "update README",
+			ok:      false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			commit, ok := ParseConventionalCommit(c.message)
+			if ok != c.ok {
+				t.Fatalf("ParseConventionalCommit(%q) ok = %v, want %v", c.message, ok, c.ok)
+			}
+			if !ok {
+				return
+			}
+			if commit.Type != c.commitType {
+				t.Errorf("Type = %q, want %q", commit.Type, c.commitType)
+			}
+			if commit.Scope != c.scope {
+				t.Errorf("Scope = %q, want %q", commit.Scope, c.scope)
+			}
+			if commit.Breaking != c.breaking {
+				t.Errorf("Breaking = %v, want %v", commit.Breaking, c.breaking)
+			}
+		})
+	}
+}
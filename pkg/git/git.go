@@ -3,15 +3,14 @@ package git
 import (
 	"fmt"
 	"log"
-	"os"
 	"regexp"
 	"strings"
 
 	"github.com/coreos/go-semver/semver"
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/pkg/errors"
-	git "gopkg.in/src-d/go-git.v4"
-	"gopkg.in/src-d/go-git.v4/plumbing"
-	"gopkg.in/src-d/go-git.v4/plumbing/object"
 )
 
 // BranchSettings contains flags that determine how branches are handled when calculating versions.
@@ -20,6 +19,9 @@ type BranchSettings struct {
 	TrimBranchPrefix          bool
 	IgnoreEnvVars             bool
 	DefaultBranch             plumbing.ReferenceName
+	// CIProvider forces which CIProvider's environment variables are consulted for the
+	// current tag/branch. Empty means auto-detect via DetectCIProvider.
+	CIProvider string
 }
 
 type gitVersion struct {
@@ -34,24 +36,54 @@ type gitVersion struct {
 
 // GetCurrentVersion returns the current version
 func GetCurrentVersion(r *git.Repository, settings *Settings, branchSettings *BranchSettings, verbose bool) (version string, err error) {
-	tag, ok := os.LookupEnv("TRAVIS_TAG")
-	if !branchSettings.IgnoreEnvVars && ok && tag != "" { // If this is a tagged build in travis shortcircuit here
-		version, err := parseTag(tag)
-		if err != nil {
-			return "", err
+	ci, err := resolveCIProvider(branchSettings.CIProvider)
+	if err != nil {
+		return "", errors.Wrap(err, "GetCurrentVersion failed")
+	}
+
+	if !branchSettings.IgnoreEnvVars && ci != nil {
+		if tag, ok := ci.Tag(); ok { // If this is a tagged build, shortcircuit here
+			version, err := parseTag(tag)
+			if err != nil {
+				return "", err
+			}
+			if verbose {
+				log.Printf("Version determined using %s tag", ci.Name())
+			}
+			return version.String(), err
 		}
-		if verbose {
-			log.Printf("Version determined using TRAVIS_TAG")
+	}
+
+	tagMap, err := buildTagMap(r, verbose)
+	if err != nil {
+		return "", errors.Wrap(err, "GetCurrentVersion failed")
+	}
+
+	h, err := r.Head()
+	if err != nil {
+		return "", errors.Wrap(err, "GetCurrentVersion failed")
+	}
+
+	v, err := getVersion(r, h, tagMap, branchSettings, settings, verbose)
+	if err != nil {
+		if _, ok := err.(*ErrShallowHistory); ok {
+			return "", err
 		}
-		return version.String(), err
+		return "", errors.Wrap(err, "GetCurrentVersion failed")
 	}
 
+	return v.String(), nil
+}
+
+// buildTagMap indexes every lightweight and annotated tag in the repository by the hash
+// of the commit it points at, so callers can look up a commit's tag name in O(1).
+func buildTagMap(r *git.Repository, verbose bool) (map[string]string, error) {
 	tagMap := make(map[string]string)
 
 	// lightweight tags
 	ltags, err := r.Tags()
 	if err != nil {
-		return "", errors.Wrap(err, "get tags failed")
+		return nil, errors.Wrap(err, "get tags failed")
 	}
 
 	err = ltags.ForEach(func(ref *plumbing.Reference) error {
@@ -63,11 +95,14 @@ func GetCurrentVersion(r *git.Repository, settings *Settings, branchSettings *Br
 		tagMap[ref.Hash().String()] = tag
 		return nil
 	})
+	if err != nil {
+		return nil, errors.Wrap(err, "get tags failed")
+	}
 
 	// annotated tags
 	tags, err := r.TagObjects()
 	if err != nil {
-		return "", errors.Wrap(err, "get tag objects failed")
+		return nil, errors.Wrap(err, "get tag objects failed")
 	}
 
 	err = tags.ForEach(func(ref *object.Tag) error {
@@ -82,20 +117,10 @@ func GetCurrentVersion(r *git.Repository, settings *Settings, branchSettings *Br
 		return nil
 	})
 	if err != nil {
-		return "", errors.Wrap(err, "GetCurrentVersion failed")
+		return nil, errors.Wrap(err, "get tag objects failed")
 	}
 
-	h, err := r.Head()
-	if err != nil {
-		return "", errors.Wrap(err, "GetCurrentVersion failed")
-	}
-
-	v, err := getVersion(r, h, tagMap, branchSettings, settings, verbose)
-	if err != nil {
-		return "", errors.Wrap(err, "GetCurrentVersion failed")
-	}
-
-	return v.String(), nil
+	return tagMap, nil
 }
 
 // GetPrereleaseLabel returns the prerelease label for the current branch
@@ -139,21 +164,25 @@ func getDefaultBranch(r *git.Repository, defaultBranch plumbing.ReferenceName, v
 
 	origin, err := r.Remote("origin")
 	if err == nil {
-		ref, err = tryResolve("refs/remotes/origin/HEAD")
+		originHeadRef, err := tryResolve("refs/remotes/origin/HEAD")
 		if err == nil {
 			for _, rs := range origin.Config().Fetch {
 				rs := rs.Reverse()
-				if rs.Match(ref.Name()) {
-					localRefName := rs.Dst(ref.Name())
+				if rs.Match(originHeadRef.Name()) {
+					localRefName := rs.Dst(originHeadRef.Name())
 					if string(localRefName[0]) == "+" {
 						localRefName = localRefName[1:]
 					}
-					ref, err = tryResolve(localRefName)
-					if err == nil {
+					if ref, err := tryResolve(localRefName); err == nil {
 						return ref, nil
 					}
 				}
 			}
+
+			// No local branch of the remote's default name exists - common in a shallow/CI
+			// checkout that only has the remote-tracking ref. Fall back to origin/HEAD itself
+			// rather than failing, since it already resolves to the right commit.
+			return originHeadRef, nil
 		}
 	}
 
@@ -179,7 +208,15 @@ func getVersion(r *git.Repository, h *plumbing.Reference, tagMap map[string]stri
 		return nil, errors.Wrap(err, "failed to get master commit from reference")
 	}
 
-	masterWalker := newBranchWalker(r, defaultHead, tagMap, settings, true, "", verbose)
+	shallowHashes, err := getShallowHashes(r)
+	if err != nil {
+		return nil, err
+	}
+
+	masterWalker, err := newBranchWalker(defaultHead, tagMap, settings, true, "", shallowHashes, verbose)
+	if err != nil {
+		return nil, err
+	}
 	masterVersion, err := masterWalker.GetVersion()
 	if err != nil {
 		return nil, err
@@ -194,27 +231,48 @@ func getVersion(r *git.Repository, h *plumbing.Reference, tagMap map[string]stri
 		return nil, errors.Wrap(err, "getVersion failed")
 	}
 
-	walker := newBranchWalker(r, c, tagMap, settings, false, defaultBranch.Hash().String(), verbose)
+	walker, err := newBranchWalker(c, tagMap, settings, false, defaultBranch.Hash().String(), shallowHashes, verbose)
+	if err != nil {
+		return nil, err
+	}
 	versionMap, err := walker.GetVersionMap()
 	if err != nil {
 		return nil, err
 	}
 
-	var baseVersion *semver.Version
+	baseVersion, err := computeVersionFromMap(versionMap, masterVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	shortHash := h.Hash().String()[:4]
+	prerelease := fmt.Sprintf("%s-%d-%s", currentBranch, len(versionMap)-1, shortHash)
+	baseVersion.PreRelease = semver.PreRelease(prerelease)
+
+	if branchSettings.ForbidBehindDefaultBranch && baseVersion.LessThan(*masterVersion) {
+		return nil, errors.Errorf("Branch has calculated version '%s' whose version is less than master '%s'", baseVersion, masterVersion)
+	}
+
+	return baseVersion, nil
+}
+
+// computeVersionFromMap applies the base-version and bump rules shared by every branch-version
+// computation (the in-process walker's and the exec backend's alike): if the oldest entry in
+// versionMap is a tagged commit, the version builds on that tag; otherwise it builds on
+// masterVersion. Every other entry's bump is then applied, oldest to newest. masterVersion itself
+// is never mutated - the returned version is always a copy.
+func computeVersionFromMap(versionMap []gitVersion, masterVersion *semver.Version) (*semver.Version, error) {
 	index := len(versionMap) - 1
 	if index == -1 {
 		return nil, errors.Errorf("Cannot determine version in branch")
 	}
 
+	var baseVersion semver.Version
 	if versionMap[index].IsSolid {
-		baseVersion = versionMap[index].Name
+		baseVersion = *versionMap[index].Name
 		index--
 	} else {
-		baseVersion = masterVersion
-	}
-
-	if index < 0 {
-		return baseVersion, nil
+		baseVersion = *masterVersion
 	}
 
 	for ; index >= 0; index-- {
@@ -229,46 +287,22 @@ func getVersion(r *git.Repository, h *plumbing.Reference, tagMap map[string]stri
 		}
 	}
 
-	shortHash := h.Hash().String()[:4]
-	prerelease := fmt.Sprintf("%s-%d-%s", currentBranch, len(versionMap)-1, shortHash)
-	baseVersion.PreRelease = semver.PreRelease(prerelease)
-
-	if branchSettings.ForbidBehindDefaultBranch && baseVersion.LessThan(*masterVersion) {
-		return nil, errors.Errorf("Branch has calculated version '%s' whose version is less than master '%s'", baseVersion, masterVersion)
-	}
-
-	return baseVersion, nil
+	return &baseVersion, nil
 }
 
 func getCurrentBranch(r *git.Repository, h *plumbing.Reference, branchSettings *BranchSettings) (name string, err error) {
 	branchName := ""
 
 	if !branchSettings.IgnoreEnvVars {
-		name, ok := os.LookupEnv("TRAVIS_PULL_REQUEST_BRANCH") // Travis
-		if ok {
-			branchName, err := cleanseBranchName(name, branchSettings.TrimBranchPrefix)
-			if err != nil {
-				return "", err
-			}
-			return branchName, nil
-		}
-
-		name, ok = os.LookupEnv("TRAVIS_BRANCH")
-		if ok {
-			branchName, err := cleanseBranchName(name, branchSettings.TrimBranchPrefix)
-			if err != nil {
-				return "", err
-			}
-			return branchName, nil
+		ci, err := resolveCIProvider(branchSettings.CIProvider)
+		if err != nil {
+			return "", err
 		}
 
-		name, ok = os.LookupEnv("CI_COMMIT_REF_NAME") // GitLab
-		if ok {
-			branchName, err := cleanseBranchName(name, branchSettings.TrimBranchPrefix)
-			if err != nil {
-				return "", err
+		if ci != nil {
+			if name, ok := ci.Branch(); ok {
+				return cleanseBranchName(name, branchSettings.TrimBranchPrefix)
 			}
-			return branchName, nil
 		}
 	}
 
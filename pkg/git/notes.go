@@ -0,0 +1,207 @@
+package git
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/pkg/errors"
+)
+
+// ReleaseNotesFormat selects how a ReleaseNotes is rendered by the `notes` command.
+type ReleaseNotesFormat string
+
+const (
+	// ReleaseNotesFormatMarkdown renders one "## Heading" section per category.
+	ReleaseNotesFormatMarkdown ReleaseNotesFormat = "markdown"
+	// ReleaseNotesFormatJSON renders the ReleaseNotes struct as JSON.
+	ReleaseNotesFormatJSON ReleaseNotesFormat = "json"
+	// ReleaseNotesFormatKeepAChangelog renders an "## [Unreleased]" section following the
+	// https://keepachangelog.com/ convention.
+	ReleaseNotesFormatKeepAChangelog ReleaseNotesFormat = "keep-a-changelog"
+)
+
+// releaseNoteCategory is one of the four buckets release notes are grouped into.
+type releaseNoteCategory string
+
+const (
+	releaseNoteCategoryFeatures releaseNoteCategory = "feat"
+	releaseNoteCategoryFixes    releaseNoteCategory = "fix"
+	releaseNoteCategoryBreaking releaseNoteCategory = "breaking"
+	releaseNoteCategoryOther    releaseNoteCategory = "other"
+)
+
+// defaultReleaseNoteOrder is the order sections are emitted in when settings doesn't override it.
+var defaultReleaseNoteOrder = []releaseNoteCategory{
+	releaseNoteCategoryFeatures,
+	releaseNoteCategoryFixes,
+	releaseNoteCategoryBreaking,
+	releaseNoteCategoryOther,
+}
+
+// defaultReleaseNoteHeadings maps each category to the heading used when settings doesn't
+// override it.
+var defaultReleaseNoteHeadings = map[releaseNoteCategory]string{
+	releaseNoteCategoryFeatures: "Features",
+	releaseNoteCategoryFixes:    "Fixes",
+	releaseNoteCategoryBreaking: "Breaking Changes",
+	releaseNoteCategoryOther:    "Other",
+}
+
+// ReleaseNoteEntry is a single commit represented in a ReleaseNotesSection.
+type ReleaseNoteEntry struct {
+	Type        string `json:"type"`
+	Scope       string `json:"scope,omitempty"`
+	Description string `json:"description"`
+	Commit      string `json:"commit"`
+}
+
+// ReleaseNotesSection groups every ReleaseNoteEntry of a given category under one heading.
+type ReleaseNotesSection struct {
+	Heading string             `json:"heading"`
+	Entries []ReleaseNoteEntry `json:"entries"`
+}
+
+// ReleaseNotes is the categorized commit log between the previous semver tag and HEAD.
+type ReleaseNotes struct {
+	Sections []ReleaseNotesSection `json:"sections"`
+}
+
+// GetReleaseNotes walks commits between the nearest semver tag reachable from HEAD (exclusive)
+// and HEAD (inclusive), and groups them by conventional-commit type into Features / Fixes /
+// Breaking Changes / Other, using settings.ReleaseNoteHeadings to relabel any of those four
+// headings.
+func GetReleaseNotes(r *gogit.Repository, settings *Settings, verbose bool) (*ReleaseNotes, error) {
+	tagMap, err := buildTagMap(r, verbose)
+	if err != nil {
+		return nil, errors.Wrap(err, "GetReleaseNotes failed")
+	}
+
+	h, err := r.Head()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetReleaseNotes failed")
+	}
+
+	commits, err := r.Log(&gogit.LogOptions{From: h.Hash()})
+	if err != nil {
+		return nil, errors.Wrap(err, "GetReleaseNotes failed")
+	}
+	defer commits.Close()
+
+	buckets := map[releaseNoteCategory][]ReleaseNoteEntry{}
+	err = commits.ForEach(func(c *object.Commit) error {
+		if tag, ok := tagMap[c.Hash.String()]; ok {
+			if _, err := parseTag(tag); err == nil {
+				return storer.ErrStop
+			}
+		}
+
+		category := releaseNoteCategoryOther
+		entry := ReleaseNoteEntry{
+			Type:        "other",
+			Description: c.Message,
+			Commit:      c.Hash.String()[:7],
+		}
+
+		if cc, ok := ParseConventionalCommit(c.Message); ok {
+			entry.Type = cc.Type
+			entry.Scope = cc.Scope
+			entry.Description = cc.Description
+
+			switch {
+			case cc.Breaking:
+				category = releaseNoteCategoryBreaking
+			case cc.Type == "feat":
+				category = releaseNoteCategoryFeatures
+			case cc.Type == "fix":
+				category = releaseNoteCategoryFixes
+			}
+		}
+
+		buckets[category] = append(buckets[category], entry)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "GetReleaseNotes failed")
+	}
+
+	notes := &ReleaseNotes{}
+	for _, category := range defaultReleaseNoteOrder {
+		entries := buckets[category]
+		if len(entries) == 0 {
+			continue
+		}
+
+		heading := defaultReleaseNoteHeadings[category]
+		if settings.ReleaseNoteHeadings != nil {
+			if override, ok := settings.ReleaseNoteHeadings[string(category)]; ok && override != "" {
+				heading = override
+			}
+		}
+
+		notes.Sections = append(notes.Sections, ReleaseNotesSection{
+			Heading: heading,
+			Entries: entries,
+		})
+	}
+
+	return notes, nil
+}
+
+// Render formats the release notes according to format.
+func (n *ReleaseNotes) Render(format ReleaseNotesFormat) (string, error) {
+	switch format {
+	case ReleaseNotesFormatJSON:
+		return n.renderJSON()
+	case ReleaseNotesFormatKeepAChangelog:
+		return n.renderKeepAChangelog(), nil
+	case ReleaseNotesFormatMarkdown, "":
+		return n.renderMarkdown(), nil
+	default:
+		return "", errors.Errorf("unknown release notes format %q", format)
+	}
+}
+
+func (n *ReleaseNotes) renderMarkdown() string {
+	var b bytes.Buffer
+	for _, s := range n.Sections {
+		fmt.Fprintf(&b, "## %s\n\n", s.Heading)
+		for _, e := range s.Entries {
+			if e.Scope != "" {
+				fmt.Fprintf(&b, "- **%s:** %s (%s)\n", e.Scope, e.Description, e.Commit)
+			} else {
+				fmt.Fprintf(&b, "- %s (%s)\n", e.Description, e.Commit)
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func (n *ReleaseNotes) renderKeepAChangelog() string {
+	var b bytes.Buffer
+	b.WriteString("## [Unreleased]\n\n")
+	for _, s := range n.Sections {
+		fmt.Fprintf(&b, "### %s\n\n", s.Heading)
+		for _, e := range s.Entries {
+			if e.Scope != "" {
+				fmt.Fprintf(&b, "- **%s:** %s\n", e.Scope, e.Description)
+			} else {
+				fmt.Fprintf(&b, "- %s\n", e.Description)
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func (n *ReleaseNotes) renderJSON() (string, error) {
+	b, err := json.MarshalIndent(n, "", "  ")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to render release notes as JSON")
+	}
+	return string(b), nil
+}
@@ -0,0 +1,63 @@
+package git
+
+import (
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/pkg/errors"
+)
+
+// OpenOptions controls how OpenRepository locates a repository. It lets library consumers
+// either point gogitver at a path to discover (a worktree checkout, a bare repo, or a linked
+// worktree's separate git-dir) or hand over an already-opened *git.Repository and skip
+// filesystem discovery entirely.
+type OpenOptions struct {
+	// Repository, if set, is used as-is and every other field is ignored.
+	Repository *gogit.Repository
+	// Path is the working tree or bare repository to open.
+	Path string
+	// GitDir, if set, is opened instead of Path's own .git - this is how a linked worktree's
+	// git-dir (e.g. ".git/worktrees/<name>") is opened directly instead of following the
+	// worktree's ".git" file.
+	GitDir string
+	// DetectDotGit causes Path to be searched upward for a .git file or directory, the way
+	// `git` itself does when invoked from a subdirectory of the working tree.
+	DetectDotGit bool
+}
+
+// OpenRepository resolves an OpenOptions into a *git.Repository, supporting plain working
+// trees, bare repositories, and linked git worktrees.
+func OpenRepository(opts *OpenOptions) (*gogit.Repository, error) {
+	if opts.Repository != nil {
+		return opts.Repository, nil
+	}
+
+	path := opts.Path
+	detectDotGit := opts.DetectDotGit
+	if opts.GitDir != "" {
+		// GitDir already names the exact git directory (e.g. a bare repo or a linked
+		// worktree's git-dir), so there's nothing to detect by walking up from it.
+		path = opts.GitDir
+		detectDotGit = false
+	}
+
+	// EnableDotGitCommonDir follows a linked worktree's ".git" file (or an explicit
+	// worktree git-dir) back to the main repository's common dir, which is where refs,
+	// objects and HEAD-for-ref-resolution actually live; without it, Head() on a worktree
+	// fails with "reference not found" even though the open itself succeeds.
+	r, err := gogit.PlainOpenWithOptions(path, &gogit.PlainOpenOptions{
+		DetectDotGit:          detectDotGit,
+		EnableDotGitCommonDir: true,
+	})
+	if err != nil && detectDotGit {
+		// path may itself be a bare repository: DetectDotGit's upward search for a ".git"
+		// entry doesn't recognize a bare repo's own root as a valid starting point, so
+		// retry opening it directly.
+		r, err = gogit.PlainOpenWithOptions(path, &gogit.PlainOpenOptions{
+			EnableDotGitCommonDir: true,
+		})
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open repository at %s", path)
+	}
+
+	return r, nil
+}
@@ -0,0 +1,232 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/pkg/errors"
+)
+
+// RepoPlan describes the release gogitver would make for a single repository if
+// ExecuteRelease were called with it.
+type RepoPlan struct {
+	Path           string
+	CurrentVersion string
+	NextVersion    string
+	Reason         string
+}
+
+// PlanRelease computes, for each repo path, the version it is currently tagged at and the
+// version gogitver would tag next. It makes no changes to any repository, so the result can
+// be printed as a dry-run and reviewed before being handed to ExecuteRelease.
+func PlanRelease(paths []string, settings *Settings, branchSettings *BranchSettings, verbose bool) ([]RepoPlan, error) {
+	repoPaths, err := expandWorkspacePaths(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	plans := make([]RepoPlan, 0, len(repoPaths))
+
+	for _, p := range repoPaths {
+		r, err := OpenRepository(&OpenOptions{Path: p, DetectDotGit: true})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to open repository at %s", p)
+		}
+
+		h, err := r.Head()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to resolve HEAD for %s", p)
+		}
+
+		current, err := previousTag(r, h, verbose)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to find previous tag for %s", p)
+		}
+
+		next, err := GetCurrentVersion(r, settings, branchSettings, verbose)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to compute next version for %s", p)
+		}
+
+		reason := fmt.Sprintf("commits since %s require a release", current)
+		if current == next {
+			reason = fmt.Sprintf("no commits requiring a bump since %s", current)
+		}
+
+		plans = append(plans, RepoPlan{
+			Path:           p,
+			CurrentVersion: current,
+			NextVersion:    next,
+			Reason:         reason,
+		})
+	}
+
+	return plans, nil
+}
+
+// expandWorkspacePaths resolves each input path to one or more repository paths: a path that
+// is itself a repository (working tree or bare) is returned as-is, while a path that is a plain
+// directory is treated as a workspace root and expanded to its immediate subdirectories that are
+// repositories. Subdirectories that aren't repositories are skipped.
+func expandWorkspacePaths(paths []string) ([]string, error) {
+	result := make([]string, 0, len(paths))
+
+	for _, p := range paths {
+		if isRepository(p) {
+			result = append(result, p)
+			continue
+		}
+
+		entries, err := os.ReadDir(p)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s is neither a repository nor a readable workspace root", p)
+		}
+
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+		found := false
+		for _, e := range entries {
+			if !e.IsDir() {
+				continue
+			}
+			sub := filepath.Join(p, e.Name())
+			if isRepository(sub) {
+				result = append(result, sub)
+				found = true
+			}
+		}
+
+		if !found {
+			return nil, errors.Errorf("%s is not a repository and none of its immediate subdirectories are repositories either", p)
+		}
+	}
+
+	return result, nil
+}
+
+// isRepository reports whether path is a git repository - either a working tree with a .git
+// directory/file, or a bare repository.
+func isRepository(path string) bool {
+	_, err := OpenRepository(&OpenOptions{Path: path, DetectDotGit: false})
+	return err == nil
+}
+
+// ExecuteRelease applies a release plan produced by PlanRelease by creating an annotated tag
+// for each repo whose next version differs from its current one, and optionally pushing those
+// tags to "origin". If signKey is non-nil, tags are signed with it. It is all-or-nothing: if
+// tagging or pushing any repo fails, every tag created (and, if already pushed, deleted from
+// "origin" too) during this call is rolled back so a partially tagged monorepo is never left
+// behind.
+func ExecuteRelease(plans []RepoPlan, push bool, signKey *openpgp.Entity) (err error) {
+	type createdTag struct {
+		repo *gogit.Repository
+		name string
+	}
+	created := make([]createdTag, 0, len(plans))
+	pushed := make([]createdTag, 0, len(plans))
+
+	rollback := func() {
+		for _, c := range pushed {
+			_ = c.repo.Push(&gogit.PushOptions{
+				RefSpecs: []config.RefSpec{config.RefSpec(fmt.Sprintf(":refs/tags/%s", c.name))},
+			})
+		}
+		for _, c := range created {
+			_ = c.repo.DeleteTag(c.name)
+		}
+	}
+
+	for _, p := range plans {
+		if p.CurrentVersion == p.NextVersion {
+			continue
+		}
+
+		r, err := OpenRepository(&OpenOptions{Path: p.Path, DetectDotGit: true})
+		if err != nil {
+			rollback()
+			return errors.Wrapf(err, "failed to open repository at %s", p.Path)
+		}
+
+		h, err := r.Head()
+		if err != nil {
+			rollback()
+			return errors.Wrapf(err, "failed to resolve HEAD for %s", p.Path)
+		}
+
+		tagName := "v" + p.NextVersion
+		_, err = r.CreateTag(tagName, h.Hash(), &gogit.CreateTagOptions{
+			Message: fmt.Sprintf("Release %s", p.NextVersion),
+			SignKey: signKey,
+		})
+		if err != nil {
+			rollback()
+			return errors.Wrapf(err, "failed to tag %s at %s", p.Path, tagName)
+		}
+
+		created = append(created, createdTag{repo: r, name: tagName})
+	}
+
+	if !push {
+		return nil
+	}
+
+	for _, c := range created {
+		err := c.repo.Push(&gogit.PushOptions{
+			RefSpecs: []config.RefSpec{config.RefSpec(fmt.Sprintf("refs/tags/%s:refs/tags/%s", c.name, c.name))},
+		})
+		if err != nil && err != gogit.NoErrAlreadyUpToDate {
+			rollback()
+			return errors.Wrapf(err, "failed to push tag %s", c.name)
+		}
+
+		pushed = append(pushed, c)
+	}
+
+	return nil
+}
+
+// previousTag walks the commit ancestry of h looking for the nearest tagged commit, returning
+// its parsed semver or "0.0.0" if the branch has never been tagged.
+func previousTag(r *gogit.Repository, h *plumbing.Reference, verbose bool) (string, error) {
+	tagMap, err := buildTagMap(r, verbose)
+	if err != nil {
+		return "", err
+	}
+
+	commits, err := r.Log(&gogit.LogOptions{From: h.Hash()})
+	if err != nil {
+		return "", err
+	}
+	defer commits.Close()
+
+	found := ""
+	err = commits.ForEach(func(c *object.Commit) error {
+		if tag, ok := tagMap[c.Hash.String()]; ok {
+			found = tag
+			return storer.ErrStop
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if found == "" {
+		return "0.0.0", nil
+	}
+
+	v, err := parseTag(found)
+	if err != nil {
+		return "", err
+	}
+
+	return v.String(), nil
+}
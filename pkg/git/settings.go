@@ -0,0 +1,71 @@
+package git
+
+import (
+	"io"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// CommitConvention selects how a commit message is interpreted to determine the kind of
+// version bump, if any, that the commit should trigger.
+type CommitConvention string
+
+const (
+	// CommitConventionRegex matches commit messages against the regexes configured on
+	// Settings. This is the default, backwards-compatible behavior.
+	CommitConventionRegex CommitConvention = "regex"
+	// CommitConventionConventional parses commit messages as Conventional Commits
+	// (https://www.conventionalcommits.org/), mapping each commit's type to a bump via
+	// ConventionalCommitTypes.
+	CommitConventionConventional CommitConvention = "conventional"
+)
+
+// Settings contains the `.gogitver.yaml` configuration used to determine how bumps are
+// detected from commit messages.
+type Settings struct {
+	MajorVersionRegex string `yaml:"majorVersionRegex"`
+	MinorVersionRegex string `yaml:"minorVersionRegex"`
+	PatchVersionRegex string `yaml:"patchVersionRegex"`
+
+	// CommitConvention selects the bump-detection engine. Defaults to CommitConventionRegex.
+	CommitConvention CommitConvention `yaml:"commitConvention"`
+	// ConventionalCommitTypes maps a Conventional Commits type (e.g. "feat") to the bump it
+	// triggers ("major", "minor", or "patch"). Only consulted when CommitConvention is
+	// CommitConventionConventional. "feat" -> "minor" and "fix" -> "patch" apply unless
+	// overridden here; any other type not present here triggers no bump.
+	ConventionalCommitTypes map[string]string `yaml:"conventionalCommitTypes"`
+
+	// ReleaseNoteHeadings overrides the heading used for one of the `notes` command's four
+	// sections: "feat", "fix", "breaking", or "other".
+	ReleaseNoteHeadings map[string]string `yaml:"releaseNoteHeadings"`
+}
+
+// GetDefaultSettings returns the settings gogitver uses when no `.gogitver.yaml` is present.
+func GetDefaultSettings() *Settings {
+	return &Settings{
+		MajorVersionRegex: `\+semver:\s?(breaking|major)`,
+		MinorVersionRegex: `\+semver:\s?(feature|minor)`,
+		PatchVersionRegex: `\+semver:\s?(fix|patch)`,
+		CommitConvention:  CommitConventionRegex,
+	}
+}
+
+// GetSettingsFromFile reads and parses a `.gogitver.yaml` document, falling back to the
+// default settings for any field the document doesn't set.
+func GetSettingsFromFile(r io.Reader) (*Settings, error) {
+	settings := GetDefaultSettings()
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read settings file")
+	}
+
+	err = yaml.Unmarshal(b, settings)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse settings file")
+	}
+
+	return settings, nil
+}
@@ -0,0 +1,52 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/pkg/errors"
+)
+
+// ErrShallowHistory is returned by GetCurrentVersion when the repository is a shallow clone
+// and the walker ran out of history before finding a tag to base the version on. CI systems
+// that default to a shallow checkout (e.g. GitHub Actions' fetch-depth: 1) hit this routinely;
+// the fix is either a deeper/full fetch, or passing --auto-unshallow to have gogitver fetch the
+// missing history itself before retrying.
+type ErrShallowHistory struct {
+	// MinDepth is the minimum history depth, in commits, gogitver needs: one more than the
+	// number of commits it walked before running out of history.
+	MinDepth int
+}
+
+func (e *ErrShallowHistory) Error() string {
+	return fmt.Sprintf("repository is a shallow clone; at least %d commits of history are needed to determine the version (fetch more history, or pass --auto-unshallow)", e.MinDepth)
+}
+
+// getShallowHashes returns the set of commit hashes (as hex strings) that a branchWalker
+// should treat as shallow boundaries rather than the true root of history.
+func getShallowHashes(r *gogit.Repository) (map[string]bool, error) {
+	hashes, err := r.Storer.Shallow()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to determine if repository is shallow")
+	}
+
+	result := make(map[string]bool, len(hashes))
+	for _, h := range hashes {
+		result[h.String()] = true
+	}
+	return result, nil
+}
+
+// AutoUnshallow fetches the full history (and all tags) for the repository checked out at
+// path, converting a shallow clone into a complete one.
+func AutoUnshallow(path string) error {
+	cmd := exec.Command("git", "fetch", "--unshallow", "--tags")
+	cmd.Dir = path
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return errors.Wrapf(err, "git fetch --unshallow --tags failed: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package git
+
+import (
+	"os"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/pkg/errors"
+)
+
+// LoadSignKey reads an armored PGP private key from path and returns the first entity in it,
+// decrypting its private key material with passphrase if it's encrypted. It's used to sign the
+// annotated tags ExecuteRelease creates; an empty path means "don't sign" and callers should
+// pass a nil *openpgp.Entity to ExecuteRelease instead of calling this.
+func LoadSignKey(path string, passphrase []byte) (*openpgp.Entity, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open sign key %s", path)
+	}
+	defer f.Close()
+
+	entityList, err := openpgp.ReadArmoredKeyRing(f)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read sign key %s", path)
+	}
+	if len(entityList) == 0 {
+		return nil, errors.Errorf("sign key %s contains no keys", path)
+	}
+
+	entity := entityList[0]
+	if entity.PrivateKey != nil && entity.PrivateKey.Encrypted {
+		if err := entity.PrivateKey.Decrypt(passphrase); err != nil {
+			return nil, errors.Wrapf(err, "failed to decrypt sign key %s", path)
+		}
+	}
+
+	return entity, nil
+}
@@ -0,0 +1,18 @@
+package git
+
+import (
+	"strings"
+
+	"github.com/coreos/go-semver/semver"
+	"github.com/pkg/errors"
+)
+
+// parseTag strips a leading "v" (if present) and parses the remainder as a semver version.
+func parseTag(tag string) (*semver.Version, error) {
+	clean := strings.TrimPrefix(tag, "v")
+	v, err := semver.NewVersion(clean)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse tag %s as semver", tag)
+	}
+	return v, nil
+}
@@ -0,0 +1,179 @@
+package git
+
+import (
+	"regexp"
+
+	"github.com/coreos/go-semver/semver"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/pkg/errors"
+)
+
+// commitParser classifies a commit message into the kind of version bump it should trigger.
+// Only one of major/minor/patch is ever true for a given commit; a major bump always wins
+// over a minor or patch bump detected for the same commit.
+type commitParser interface {
+	Parse(message string) (major, minor, patch bool)
+}
+
+// newCommitParser selects a commitParser based on settings.CommitConvention.
+func newCommitParser(settings *Settings) (commitParser, error) {
+	switch settings.CommitConvention {
+	case CommitConventionConventional:
+		return newConventionalCommitParser(settings), nil
+	default:
+		return newRegexCommitParser(settings)
+	}
+}
+
+// regexCommitParser implements commitParser using the MajorVersionRegex/MinorVersionRegex/
+// PatchVersionRegex patterns configured on Settings. This is gogitver's original behavior.
+type regexCommitParser struct {
+	major *regexp.Regexp
+	minor *regexp.Regexp
+	patch *regexp.Regexp
+}
+
+func newRegexCommitParser(settings *Settings) (*regexCommitParser, error) {
+	major, err := regexp.Compile(settings.MajorVersionRegex)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to compile majorVersionRegex")
+	}
+
+	minor, err := regexp.Compile(settings.MinorVersionRegex)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to compile minorVersionRegex")
+	}
+
+	patch, err := regexp.Compile(settings.PatchVersionRegex)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to compile patchVersionRegex")
+	}
+
+	return &regexCommitParser{major: major, minor: minor, patch: patch}, nil
+}
+
+func (p *regexCommitParser) Parse(message string) (major, minor, patch bool) {
+	major = p.major.MatchString(message)
+	minor = !major && p.minor.MatchString(message)
+	patch = !major && !minor && p.patch.MatchString(message)
+	return major, minor, patch
+}
+
+// branchWalker walks a branch's commit ancestry, from its tip down to the nearest tagged
+// ("solid") commit, classifying each commit along the way with a commitParser.
+type branchWalker struct {
+	start           *object.Commit
+	tagMap          map[string]string
+	isDefaultBranch bool
+	stopHash        string
+	verbose         bool
+	parser          commitParser
+	// shallowHashes is the set of commit hashes the repository deliberately didn't fetch
+	// parents for. A nil/empty set means the repository isn't a shallow clone.
+	shallowHashes map[string]bool
+}
+
+func newBranchWalker(start *object.Commit, tagMap map[string]string, settings *Settings, isDefaultBranch bool, stopHash string, shallowHashes map[string]bool, verbose bool) (*branchWalker, error) {
+	parser, err := newCommitParser(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	return &branchWalker{
+		start:           start,
+		tagMap:          tagMap,
+		isDefaultBranch: isDefaultBranch,
+		stopHash:        stopHash,
+		verbose:         verbose,
+		parser:          parser,
+		shallowHashes:   shallowHashes,
+	}, nil
+}
+
+// GetVersionMap walks from the branch tip to the nearest solid tag (or the root of history,
+// or stopHash, whichever comes first), returning one gitVersion per commit visited in that
+// order (tip first, tagged commit last). The stopHash commit itself is not included.
+func (w *branchWalker) GetVersionMap() ([]gitVersion, error) {
+	result := []gitVersion{}
+	commit := w.start
+
+	for {
+		if commit.Hash.String() == w.stopHash {
+			// stopHash marks the default branch's tip, whose bump is already folded into
+			// the default branch's own version; counting it again here would double-apply it.
+			break
+		}
+
+		isSolid := false
+		var name *semver.Version
+		if tag, ok := w.tagMap[commit.Hash.String()]; ok {
+			v, err := parseTag(tag)
+			if err == nil {
+				isSolid = true
+				name = v
+			}
+		}
+
+		major, minor, patch := w.parser.Parse(commit.Message)
+
+		result = append(result, gitVersion{
+			IsSolid:   isSolid,
+			Name:      name,
+			MajorBump: major,
+			MinorBump: minor,
+			PatchBump: patch,
+			Commit:    commit.Hash.String(),
+		})
+
+		if isSolid {
+			break
+		}
+
+		parent, err := commit.Parent(0)
+		if err != nil {
+			if w.shallowHashes[commit.Hash.String()] {
+				return result, &ErrShallowHistory{MinDepth: len(result) + 1}
+			}
+			break
+		}
+		commit = parent
+	}
+
+	return result, nil
+}
+
+// GetVersion returns the version for the branch tip: the nearest solid tag, bumped once per
+// commit walked since that tag according to each commit's classification.
+func (w *branchWalker) GetVersion() (*semver.Version, error) {
+	versionMap, err := w.GetVersionMap()
+	if err != nil {
+		return nil, err
+	}
+
+	index := len(versionMap) - 1
+	if index == -1 {
+		return nil, errors.New("cannot determine version in branch")
+	}
+
+	var base *semver.Version
+	if versionMap[index].IsSolid {
+		base = versionMap[index].Name
+		index--
+	} else {
+		base = &semver.Version{}
+	}
+
+	for ; index >= 0; index-- {
+		v := versionMap[index]
+		switch {
+		case v.MajorBump:
+			base.BumpMajor()
+		case v.MinorBump:
+			base.BumpMinor()
+		case v.PatchBump:
+			base.BumpPatch()
+		}
+	}
+
+	return base, nil
+}